@@ -0,0 +1,140 @@
+// Package bridge implements a StatsD/Graphite ingest subsystem: it listens
+// for StatsD line protocol and Graphite plaintext samples and maps them
+// onto Prometheus collectors, following the mapping-config design used by
+// the classic statsd_exporter.
+package bridge
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// MetricType is the kind of Prometheus collector a mapping produces.
+type MetricType string
+
+const (
+	MetricCounter         MetricType = "counter"
+	MetricGauge           MetricType = "gauge"
+	MetricHistogram       MetricType = "histogram"
+	MetricNativeHistogram MetricType = "native_histogram"
+)
+
+// Mapping maps one dotted-name pattern onto a Prometheus series: the match
+// pattern (glob by default, or a regular expression), the output metric
+// name and labels (which may reference capture groups as $1, $2, ...), the
+// collector type, and an idle TTL.
+type Mapping struct {
+	Match string `yaml:"match"`
+	Regex bool   `yaml:"regex"`
+
+	Name   string            `yaml:"name"`
+	Labels map[string]string `yaml:"labels"`
+
+	Type MetricType    `yaml:"type"`
+	TTL  time.Duration `yaml:"ttl"`
+
+	NativeHistogramBucketFactor float64 `yaml:"native_histogram_bucket_factor"`
+	NativeHistogramMaxBuckets   uint32  `yaml:"native_histogram_max_buckets"`
+
+	compiled *regexp.Regexp
+}
+
+// Compile builds the anchored matcher for m from Match, turning a glob
+// pattern into a regular expression unless Regex is already set.
+func (m *Mapping) Compile() error {
+	pattern := m.Match
+	if !m.Regex {
+		pattern = globToRegex(pattern)
+	}
+	re, err := regexp.Compile("^" + pattern + "$")
+	if err != nil {
+		return fmt.Errorf("mapping %q: %w", m.Match, err)
+	}
+	m.compiled = re
+	return nil
+}
+
+// globToRegex turns "*" wildcard segments into capturing groups and
+// escapes everything else, e.g. "app.*.latency" -> "app\.([^.]+)\.latency".
+func globToRegex(glob string) string {
+	parts := strings.Split(glob, "*")
+	for i, p := range parts {
+		parts[i] = regexp.QuoteMeta(p)
+	}
+	return strings.Join(parts, "([^.]+)")
+}
+
+// matchName reports whether name matches m, returning the captured groups
+// on success.
+func (m *Mapping) matchName(name string) ([]string, bool) {
+	groups := m.compiled.FindStringSubmatch(name)
+	if groups == nil {
+		return nil, false
+	}
+	return groups[1:], true
+}
+
+// Config is the ordered list of mappings a bridge Server applies to
+// incoming samples; the first mapping to match wins.
+type Config struct {
+	Mappings []Mapping `yaml:"mappings"`
+}
+
+// LoadConfig reads and compiles a mapping config from path.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading mapping config: %w", err)
+	}
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parsing mapping config: %w", err)
+	}
+	for i := range cfg.Mappings {
+		if err := cfg.Mappings[i].Compile(); err != nil {
+			return nil, err
+		}
+	}
+	return &cfg, nil
+}
+
+// Resolve finds the first mapping matching name and expands its Name and
+// Labels templates against the captured groups.
+func (c *Config) Resolve(name string) (metricName string, labels map[string]string, mapping *Mapping, ok bool) {
+	for i := range c.Mappings {
+		m := &c.Mappings[i]
+		groups, matched := m.matchName(name)
+		if !matched {
+			continue
+		}
+		labels = make(map[string]string, len(m.Labels))
+		for k, v := range m.Labels {
+			labels[k] = expandCaptures(v, groups)
+		}
+		return expandCaptures(m.Name, groups), labels, m, true
+	}
+	return "", nil, nil, false
+}
+
+// captureRef matches a "$N" capture reference in a mapping's Name/Labels
+// template.
+var captureRef = regexp.MustCompile(`\$(\d+)`)
+
+// expandCaptures substitutes every "$N" in template with the Nth capture
+// group, in a single pass so a two-digit reference like "$10" isn't
+// clobbered by a prior replacement of "$1".
+func expandCaptures(template string, groups []string) string {
+	return captureRef.ReplaceAllStringFunc(template, func(ref string) string {
+		n, err := strconv.Atoi(ref[1:])
+		if err != nil || n < 1 || n > len(groups) {
+			return ref
+		}
+		return groups[n-1]
+	})
+}
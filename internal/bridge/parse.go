@@ -0,0 +1,76 @@
+package bridge
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// StatsDType is the type suffix on a StatsD line protocol sample.
+type StatsDType string
+
+const (
+	StatsDCounter   StatsDType = "c"
+	StatsDGauge     StatsDType = "g"
+	StatsDTimer     StatsDType = "ms"
+	StatsDHistogram StatsDType = "h"
+)
+
+// StatsDSample is one parsed StatsD line, e.g. "foo.bar:3|c|@0.1".
+type StatsDSample struct {
+	Name  string
+	Value float64
+	Type  StatsDType
+	Rate  float64
+}
+
+// ParseStatsDLine parses a single StatsD line protocol sample.
+func ParseStatsDLine(line string) (StatsDSample, error) {
+	name, rest, ok := strings.Cut(line, ":")
+	if !ok {
+		return StatsDSample{}, fmt.Errorf("statsd: missing ':' in %q", line)
+	}
+	fields := strings.Split(rest, "|")
+	if len(fields) < 2 {
+		return StatsDSample{}, fmt.Errorf("statsd: malformed sample %q", line)
+	}
+	value, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return StatsDSample{}, fmt.Errorf("statsd: bad value in %q: %w", line, err)
+	}
+
+	sample := StatsDSample{Name: name, Value: value, Type: StatsDType(fields[1]), Rate: 1}
+	for _, f := range fields[2:] {
+		if rate, ok := strings.CutPrefix(f, "@"); ok {
+			if v, err := strconv.ParseFloat(rate, 64); err == nil && v > 0 {
+				sample.Rate = v
+			}
+		}
+	}
+	return sample, nil
+}
+
+// GraphiteSample is one parsed Graphite plaintext sample, e.g.
+// "foo.bar 3.5 1690000000".
+type GraphiteSample struct {
+	Name      string
+	Value     float64
+	Timestamp int64
+}
+
+// ParseGraphiteLine parses a single Graphite plaintext sample.
+func ParseGraphiteLine(line string) (GraphiteSample, error) {
+	fields := strings.Fields(line)
+	if len(fields) != 3 {
+		return GraphiteSample{}, fmt.Errorf("graphite: expected 3 fields, got %q", line)
+	}
+	value, err := strconv.ParseFloat(fields[1], 64)
+	if err != nil {
+		return GraphiteSample{}, fmt.Errorf("graphite: bad value in %q: %w", line, err)
+	}
+	ts, err := strconv.ParseInt(fields[2], 10, 64)
+	if err != nil {
+		return GraphiteSample{}, fmt.Errorf("graphite: bad timestamp in %q: %w", line, err)
+	}
+	return GraphiteSample{Name: fields[0], Value: value, Timestamp: ts}, nil
+}
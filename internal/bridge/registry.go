@@ -0,0 +1,145 @@
+package bridge
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// applier is the subset of collector behaviour the bridge drives. It
+// mirrors internal/registry's applier interface but is kept local so this
+// package has no dependency on the scenario subsystem.
+type applier interface {
+	Inc()
+	Add(float64)
+	Set(float64)
+	Observe(float64)
+}
+
+// collectorKey identifies a registered collector by its metric name and
+// label set, the same dedupe strategy used by the classic statsd_exporter.
+type collectorKey uint64
+
+func hashNameAndLabels(name string, labels map[string]string) collectorKey {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(h, "\x00%s\x00%s", k, labels[k])
+	}
+	return collectorKey(h.Sum64())
+}
+
+// entry bundles a collector with the bookkeeping needed to expire it once
+// its mapping's TTL has elapsed since the last sample.
+type entry struct {
+	applier
+	collector prometheus.Collector
+	ttl       time.Duration
+	lastSeen  time.Time
+}
+
+func (e *entry) expired(now time.Time) bool {
+	return e.ttl > 0 && now.Sub(e.lastSeen) > e.ttl
+}
+
+// Registry deduplicates bridge collectors by (name, labels) and expires
+// ones that go idle past their mapping's TTL.
+type Registry struct {
+	mu       sync.Mutex
+	entries  map[collectorKey]*entry
+	registry prometheus.Registerer
+}
+
+// NewRegistry builds a Registry that registers collectors with reg.
+func NewRegistry(reg prometheus.Registerer) *Registry {
+	return &Registry{entries: make(map[collectorKey]*entry), registry: reg}
+}
+
+// GetOrCreate returns the collector for (name, labels), constructing and
+// registering it with Prometheus on first use according to m.Type.
+func (r *Registry) GetOrCreate(name string, labels map[string]string, m *Mapping) (applier, error) {
+	key := hashNameAndLabels(name, labels)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if e, ok := r.entries[key]; ok {
+		e.lastSeen = time.Now()
+		return e.applier, nil
+	}
+
+	a, collector, err := r.build(name, labels, m)
+	if err != nil {
+		return nil, err
+	}
+	r.entries[key] = &entry{applier: a, collector: collector, ttl: m.TTL, lastSeen: time.Now()}
+	return a, nil
+}
+
+func (r *Registry) build(name string, labels map[string]string, m *Mapping) (applier, prometheus.Collector, error) {
+	constLabels := prometheus.Labels(labels)
+	switch m.Type {
+	case MetricCounter:
+		c := prometheus.NewCounter(prometheus.CounterOpts{Name: name, Help: "Bridged from statsd/graphite.", ConstLabels: constLabels})
+		if err := r.registry.Register(c); err != nil {
+			return nil, nil, err
+		}
+		return counterApplier{c}, c, nil
+	case MetricGauge:
+		g := prometheus.NewGauge(prometheus.GaugeOpts{Name: name, Help: "Bridged from statsd/graphite.", ConstLabels: constLabels})
+		if err := r.registry.Register(g); err != nil {
+			return nil, nil, err
+		}
+		return gaugeApplier{g}, g, nil
+	case MetricHistogram, MetricNativeHistogram:
+		opts := prometheus.HistogramOpts{Name: name, Help: "Bridged from statsd/graphite.", ConstLabels: constLabels, Buckets: prometheus.DefBuckets}
+		if m.Type == MetricNativeHistogram {
+			opts.NativeHistogramBucketFactor = m.NativeHistogramBucketFactor
+			opts.NativeHistogramMaxBucketNumber = m.NativeHistogramMaxBuckets
+		}
+		h := prometheus.NewHistogram(opts)
+		if err := r.registry.Register(h); err != nil {
+			return nil, nil, err
+		}
+		return observerApplier{h}, h, nil
+	default:
+		return nil, nil, fmt.Errorf("mapping %s: unknown type %q", name, m.Type)
+	}
+}
+
+// Sweep unregisters every collector that has been idle past its mapping's
+// TTL. Call it periodically from a background goroutine.
+func (r *Registry) Sweep(now time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for key, e := range r.entries {
+		if e.expired(now) {
+			r.registry.Unregister(e.collector)
+			delete(r.entries, key)
+		}
+	}
+}
+
+type gaugeApplier struct{ prometheus.Gauge }
+
+func (gaugeApplier) Observe(float64) {}
+
+type counterApplier struct{ prometheus.Counter }
+
+func (counterApplier) Set(float64)     {}
+func (counterApplier) Observe(float64) {}
+
+type observerApplier struct{ prometheus.Observer }
+
+func (observerApplier) Set(float64) {}
+func (observerApplier) Inc()        {}
+func (observerApplier) Add(float64) {}
@@ -0,0 +1,149 @@
+package bridge
+
+import (
+	"bufio"
+	"log"
+	"net"
+	"strings"
+)
+
+// Server listens for StatsD and/or Graphite samples and maps them onto
+// Prometheus collectors via its Config and Registry.
+type Server struct {
+	cfg *Config
+	reg *Registry
+}
+
+// NewServer builds a Server that resolves incoming sample names against
+// cfg and registers/updates collectors in reg.
+func NewServer(cfg *Config, reg *Registry) *Server {
+	return &Server{cfg: cfg, reg: reg}
+}
+
+// ListenStatsD starts UDP and TCP listeners for StatsD line protocol on
+// address. It blocks until either listener fails.
+func (s *Server) ListenStatsD(address string) error {
+	errc := make(chan error, 2)
+	go func() { errc <- s.listenUDP(address, s.handleStatsDLine) }()
+	go func() { errc <- s.listenTCP(address, s.handleStatsDLine) }()
+	return <-errc
+}
+
+// ListenGraphite starts UDP and TCP listeners for Graphite plaintext on
+// address. It blocks until either listener fails.
+func (s *Server) ListenGraphite(address string) error {
+	errc := make(chan error, 2)
+	go func() { errc <- s.listenUDP(address, s.handleGraphiteLine) }()
+	go func() { errc <- s.listenTCP(address, s.handleGraphiteLine) }()
+	return <-errc
+}
+
+func (s *Server) listenUDP(address string, handle func(string)) error {
+	addr, err := net.ResolveUDPAddr("udp", address)
+	if err != nil {
+		return err
+	}
+	conn, err := net.ListenUDP("udp", addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	buf := make([]byte, 65535)
+	for {
+		n, _, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			return err
+		}
+		for _, line := range splitLines(string(buf[:n])) {
+			handle(line)
+		}
+	}
+}
+
+func (s *Server) listenTCP(address string, handle func(string)) error {
+	ln, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	defer ln.Close()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return err
+		}
+		go func(c net.Conn) {
+			defer c.Close()
+			scanner := bufio.NewScanner(c)
+			for scanner.Scan() {
+				handle(scanner.Text())
+			}
+		}(conn)
+	}
+}
+
+func splitLines(s string) []string {
+	var lines []string
+	for _, l := range strings.Split(s, "\n") {
+		l = strings.TrimSpace(l)
+		if l != "" {
+			lines = append(lines, l)
+		}
+	}
+	return lines
+}
+
+func (s *Server) handleStatsDLine(line string) {
+	sample, err := ParseStatsDLine(line)
+	if err != nil {
+		log.Printf("bridge: %v", err)
+		return
+	}
+	name, labels, mapping, ok := s.cfg.Resolve(sample.Name)
+	if !ok {
+		return
+	}
+	a, err := s.reg.GetOrCreate(name, labels, mapping)
+	if err != nil {
+		log.Printf("bridge: %v", err)
+		return
+	}
+	switch sample.Type {
+	case StatsDCounter:
+		a.Add(sample.Value / sample.Rate)
+	case StatsDGauge:
+		a.Set(sample.Value)
+	case StatsDTimer, StatsDHistogram:
+		a.Observe(sample.Value)
+	default:
+		log.Printf("bridge: statsd sample %q: unsupported type %q", line, sample.Type)
+	}
+}
+
+func (s *Server) handleGraphiteLine(line string) {
+	sample, err := ParseGraphiteLine(line)
+	if err != nil {
+		log.Printf("bridge: %v", err)
+		return
+	}
+	name, labels, mapping, ok := s.cfg.Resolve(sample.Name)
+	if !ok {
+		return
+	}
+	a, err := s.reg.GetOrCreate(name, labels, mapping)
+	if err != nil {
+		log.Printf("bridge: %v", err)
+		return
+	}
+	switch mapping.Type {
+	case MetricCounter:
+		a.Add(sample.Value)
+	case MetricGauge:
+		a.Set(sample.Value)
+	case MetricHistogram, MetricNativeHistogram:
+		a.Observe(sample.Value)
+	default:
+		log.Printf("bridge: graphite sample %q: unsupported mapping type %q", line, mapping.Type)
+	}
+}
@@ -0,0 +1,202 @@
+// Package exemplar generates exemplar labels for histogram and summary
+// observations, at a configurable rate, following the W3C tracecontext
+// convention for synthetic trace_id/span_id pairs.
+package exemplar
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Labeler produces the exemplar labels attached to one observation.
+type Labeler interface {
+	Labels() (prometheus.Labels, error)
+}
+
+// TraceContextLabeler synthesizes a fresh W3C tracecontext-style trace_id
+// (16 bytes, hex) and span_id (8 bytes, hex) for every observation.
+type TraceContextLabeler struct{}
+
+// Labels implements Labeler.
+func (TraceContextLabeler) Labels() (prometheus.Labels, error) {
+	traceID := make([]byte, 16)
+	if _, err := rand.Read(traceID); err != nil {
+		return nil, fmt.Errorf("generating trace_id: %w", err)
+	}
+	spanID := make([]byte, 8)
+	if _, err := rand.Read(spanID); err != nil {
+		return nil, fmt.Errorf("generating span_id: %w", err)
+	}
+	return prometheus.Labels{
+		"trace_id": hex.EncodeToString(traceID),
+		"span_id":  hex.EncodeToString(spanID),
+	}, nil
+}
+
+// FileLabeler returns a fixed set of labels read once from a "key=value
+// per line" file. It's meant to be combined with TraceContextLabeler via
+// Chain to inject static context (e.g. a deployment or region label) onto
+// every exemplar.
+type FileLabeler struct {
+	labels prometheus.Labels
+}
+
+// NewFileLabeler reads and parses path.
+func NewFileLabeler(path string) (*FileLabeler, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading exemplar labels file: %w", err)
+	}
+	labels := prometheus.Labels{}
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("exemplar labels file: malformed line %q", line)
+		}
+		labels[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	return &FileLabeler{labels: labels}, nil
+}
+
+// Labels implements Labeler.
+func (f *FileLabeler) Labels() (prometheus.Labels, error) {
+	out := make(prometheus.Labels, len(f.labels))
+	for k, v := range f.labels {
+		out[k] = v
+	}
+	return out, nil
+}
+
+// chain merges the labels of several Labelers, later ones overriding
+// earlier ones on key conflicts.
+type chain []Labeler
+
+// Chain combines labelers into a single Labeler whose output is the union
+// of all of their labels.
+func Chain(labelers ...Labeler) Labeler {
+	return chain(labelers)
+}
+
+func (c chain) Labels() (prometheus.Labels, error) {
+	out := prometheus.Labels{}
+	for _, l := range c {
+		labels, err := l.Labels()
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range labels {
+			out[k] = v
+		}
+	}
+	return out, nil
+}
+
+// rateKind distinguishes count-based from time-based sampling.
+type rateKind uint8
+
+const (
+	rateCount rateKind = iota
+	rateTime
+)
+
+// Rate describes how often exemplars should be sampled: every N
+// observations, or at most once per duration.
+type Rate struct {
+	kind  rateKind
+	count uint64
+	every time.Duration
+}
+
+// ParseRate parses the -exemplars flag value: "every=100" samples one
+// exemplar every 100 observations, "every=1s" samples at most once per
+// second.
+func ParseRate(s string) (Rate, error) {
+	value, ok := strings.CutPrefix(s, "every=")
+	if !ok {
+		return Rate{}, fmt.Errorf("exemplar rate %q: expected \"every=<N>\" or \"every=<duration>\"", s)
+	}
+	if n, err := strconv.ParseUint(value, 10, 64); err == nil {
+		if n == 0 {
+			return Rate{}, fmt.Errorf("exemplar rate %q: count must be positive", s)
+		}
+		return Rate{kind: rateCount, count: n}, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return Rate{}, fmt.Errorf("exemplar rate %q: not a count or a duration: %w", s, err)
+	}
+	return Rate{kind: rateTime, every: d}, nil
+}
+
+// Sampler decides, for a stream of observations, which ones get an
+// exemplar attached.
+type Sampler struct {
+	rate Rate
+
+	mu       sync.Mutex
+	count    uint64
+	lastSent time.Time
+}
+
+// NewSampler builds a Sampler for rate.
+func NewSampler(rate Rate) *Sampler {
+	return &Sampler{rate: rate}
+}
+
+// ShouldSample reports whether the current observation should carry an
+// exemplar.
+func (s *Sampler) ShouldSample() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch s.rate.kind {
+	case rateCount:
+		s.count++
+		return s.count%s.rate.count == 0
+	case rateTime:
+		now := time.Now()
+		if now.Sub(s.lastSent) >= s.rate.every {
+			s.lastSent = now
+			return true
+		}
+		return false
+	default:
+		return true
+	}
+}
+
+// Controller pairs a Sampler with a Labeler, giving callers a single
+// "should I, and if so with what labels" decision point.
+type Controller struct {
+	sampler *Sampler
+	labeler Labeler
+}
+
+// NewController builds a Controller sampling at rate and labeling with
+// labeler.
+func NewController(rate Rate, labeler Labeler) *Controller {
+	return &Controller{sampler: NewSampler(rate), labeler: labeler}
+}
+
+// ShouldSample reports whether the current observation should carry an
+// exemplar.
+func (c *Controller) ShouldSample() bool {
+	return c.sampler.ShouldSample()
+}
+
+// Labels produces the labels for the exemplar being attached.
+func (c *Controller) Labels() (prometheus.Labels, error) {
+	return c.labeler.Labels()
+}
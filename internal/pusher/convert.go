@@ -0,0 +1,142 @@
+package pusher
+
+import (
+	"math"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/prometheus/prompb"
+)
+
+// familiesToWriteRequest converts gathered metric families into a
+// remote-write WriteRequest. Counters, gauges, summaries and classic
+// histogram buckets become one sample series each. Histograms with a
+// native representation are emitted as a single RW 2.0-style series
+// carrying the histograms field when includeNativeHistograms is set;
+// otherwise they're downgraded to classic buckets like a normal scrape.
+func familiesToWriteRequest(families []*dto.MetricFamily, includeNativeHistograms bool) *prompb.WriteRequest {
+	wr := &prompb.WriteRequest{}
+	for _, mf := range families {
+		for _, m := range mf.GetMetric() {
+			wr.Timeseries = append(wr.Timeseries, metricToTimeSeries(mf, m, includeNativeHistograms)...)
+		}
+	}
+	return wr
+}
+
+func metricToTimeSeries(mf *dto.MetricFamily, m *dto.Metric, includeNativeHistograms bool) []prompb.TimeSeries {
+	name := mf.GetName()
+	ts := timestampMillis(m)
+	baseLabels := metricLabels(m)
+
+	switch mf.GetType() {
+	case dto.MetricType_COUNTER:
+		return []prompb.TimeSeries{sampleSeries(name, baseLabels, m.GetCounter().GetValue(), ts)}
+	case dto.MetricType_GAUGE:
+		return []prompb.TimeSeries{sampleSeries(name, baseLabels, m.GetGauge().GetValue(), ts)}
+	case dto.MetricType_SUMMARY:
+		return summarySeries(name, baseLabels, m.GetSummary(), ts)
+	case dto.MetricType_HISTOGRAM:
+		h := m.GetHistogram()
+		if includeNativeHistograms && h.Schema != nil {
+			return []prompb.TimeSeries{nativeHistogramSeries(name, baseLabels, h, ts)}
+		}
+		return classicHistogramSeries(name, baseLabels, h, ts)
+	default:
+		return nil
+	}
+}
+
+func sampleSeries(name string, labels []prompb.Label, value float64, ts int64) prompb.TimeSeries {
+	return prompb.TimeSeries{
+		Labels:  withName(labels, name),
+		Samples: []prompb.Sample{{Value: value, Timestamp: ts}},
+	}
+}
+
+func summarySeries(name string, labels []prompb.Label, s *dto.Summary, ts int64) []prompb.TimeSeries {
+	series := []prompb.TimeSeries{
+		sampleSeries(name+"_sum", labels, s.GetSampleSum(), ts),
+		sampleSeries(name+"_count", labels, float64(s.GetSampleCount()), ts),
+	}
+	for _, q := range s.GetQuantile() {
+		series = append(series, sampleSeries(name, appendLabel(labels, "quantile", formatFloat(q.GetQuantile())), q.GetValue(), ts))
+	}
+	return series
+}
+
+func classicHistogramSeries(name string, labels []prompb.Label, h *dto.Histogram, ts int64) []prompb.TimeSeries {
+	series := []prompb.TimeSeries{
+		sampleSeries(name+"_sum", labels, h.GetSampleSum(), ts),
+		sampleSeries(name+"_count", labels, float64(h.GetSampleCount()), ts),
+	}
+	infSeen := false
+	for _, b := range h.GetBucket() {
+		if math.IsInf(b.GetUpperBound(), +1) {
+			infSeen = true
+		}
+		series = append(series, sampleSeries(name+"_bucket", appendLabel(labels, "le", formatFloat(b.GetUpperBound())), float64(b.GetCumulativeCount()), ts))
+	}
+	// dto.Histogram usually carries no explicit +Inf bucket; the text/
+	// OpenMetrics encoder synthesizes it from the overall sample count at
+	// scrape time when it is absent, so we do the same here.
+	if !infSeen {
+		series = append(series, sampleSeries(name+"_bucket", appendLabel(labels, "le", "+Inf"), float64(h.GetSampleCount()), ts))
+	}
+	return series
+}
+
+// nativeHistogramSeries emits a single RW 2.0-style series carrying the
+// native histogram's buckets in the Histograms field instead of expanding
+// them into classic _bucket series.
+func nativeHistogramSeries(name string, labels []prompb.Label, h *dto.Histogram, ts int64) prompb.TimeSeries {
+	return prompb.TimeSeries{
+		Labels: withName(labels, name),
+		Histograms: []prompb.Histogram{
+			{
+				Schema:         h.GetSchema(),
+				ZeroThreshold:  h.GetZeroThreshold(),
+				ZeroCount:      &prompb.Histogram_ZeroCountInt{ZeroCountInt: h.GetZeroCount()},
+				Count:          &prompb.Histogram_CountInt{CountInt: h.GetSampleCount()},
+				Sum:            h.GetSampleSum(),
+				NegativeSpans:  convertSpans(h.GetNegativeSpan()),
+				NegativeDeltas: h.GetNegativeDelta(),
+				PositiveSpans:  convertSpans(h.GetPositiveSpan()),
+				PositiveDeltas: h.GetPositiveDelta(),
+				Timestamp:      ts,
+			},
+		},
+	}
+}
+
+func convertSpans(spans []*dto.BucketSpan) []prompb.BucketSpan {
+	out := make([]prompb.BucketSpan, len(spans))
+	for i, s := range spans {
+		out[i] = prompb.BucketSpan{Offset: s.GetOffset(), Length: s.GetLength()}
+	}
+	return out
+}
+
+func metricLabels(m *dto.Metric) []prompb.Label {
+	labels := make([]prompb.Label, 0, len(m.GetLabel()))
+	for _, l := range m.GetLabel() {
+		labels = append(labels, prompb.Label{Name: l.GetName(), Value: l.GetValue()})
+	}
+	return labels
+}
+
+func withName(labels []prompb.Label, name string) []prompb.Label {
+	return append([]prompb.Label{{Name: "__name__", Value: name}}, labels...)
+}
+
+func appendLabel(labels []prompb.Label, name, value string) []prompb.Label {
+	out := make([]prompb.Label, len(labels), len(labels)+1)
+	copy(out, labels)
+	return append(out, prompb.Label{Name: name, Value: value})
+}
+
+func timestampMillis(m *dto.Metric) int64 {
+	if ts := m.GetTimestampMs(); ts != 0 {
+		return ts
+	}
+	return nowMillis()
+}
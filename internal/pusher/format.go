@@ -0,0 +1,19 @@
+package pusher
+
+import (
+	"strconv"
+	"time"
+)
+
+// formatFloat renders a label value (quantile, bucket bound) the same way
+// the text exposition format does, so downstream tooling sees familiar
+// strings like "0.99" or "+Inf".
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// nowMillis is used when a gathered metric carries no timestamp of its
+// own, matching how a normal scrape stamps samples at collection time.
+func nowMillis() int64 {
+	return time.Now().UnixMilli()
+}
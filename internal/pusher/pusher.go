@@ -0,0 +1,123 @@
+// Package pusher gathers metrics from a prometheus.Gatherer on an interval
+// and pushes them to a remote-write-compatible endpoint, as an alternative
+// to serving /metrics for scraping.
+package pusher
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Pusher gathers and ships one batch of metrics to a sink. It's the
+// extension point other sinks (OTLP, Kafka) are expected to implement
+// alongside RemoteWritePusher.
+type Pusher interface {
+	Push(ctx context.Context) error
+}
+
+// Config controls how RemoteWritePusher talks to its endpoint.
+type Config struct {
+	URL      string
+	Interval time.Duration
+
+	Headers  map[string]string
+	Username string
+	Password string
+
+	BearerTokenFile string
+
+	TLSConfig *tls.Config
+
+	IncludeNativeHistograms bool
+}
+
+// RemoteWritePusher periodically gathers from a prometheus.Gatherer and
+// POSTs the result as a snappy-compressed protobuf WriteRequest.
+type RemoteWritePusher struct {
+	cfg      Config
+	gatherer prometheus.Gatherer
+	client   *http.Client
+}
+
+// NewRemoteWritePusher builds a RemoteWritePusher that reads from gatherer
+// and writes to cfg.URL.
+func NewRemoteWritePusher(cfg Config, gatherer prometheus.Gatherer) *RemoteWritePusher {
+	return &RemoteWritePusher{
+		cfg:      cfg,
+		gatherer: gatherer,
+		client:   &http.Client{Transport: &http.Transport{TLSClientConfig: cfg.TLSConfig}},
+	}
+}
+
+// Push gathers the current metrics and sends a single remote-write
+// request. It implements Pusher.
+func (p *RemoteWritePusher) Push(ctx context.Context) error {
+	families, err := p.gatherer.Gather()
+	if err != nil {
+		return fmt.Errorf("gathering metrics: %w", err)
+	}
+
+	writeRequest := familiesToWriteRequest(families, p.cfg.IncludeNativeHistograms)
+	data, err := writeRequest.Marshal()
+	if err != nil {
+		return fmt.Errorf("marshaling write request: %w", err)
+	}
+	compressed := snappy.Encode(nil, data)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.cfg.URL, bytes.NewReader(compressed))
+	if err != nil {
+		return fmt.Errorf("building remote-write request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	for k, v := range p.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+	if p.cfg.Username != "" {
+		req.SetBasicAuth(p.cfg.Username, p.cfg.Password)
+	}
+	if p.cfg.BearerTokenFile != "" {
+		token, err := os.ReadFile(p.cfg.BearerTokenFile)
+		if err != nil {
+			return fmt.Errorf("reading bearer token file: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("sending remote-write request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("remote-write endpoint returned %s", resp.Status)
+	}
+	return nil
+}
+
+// Run calls Push every cfg.Interval until ctx is cancelled, logging
+// failures through onError rather than stopping the loop.
+func (p *RemoteWritePusher) Run(ctx context.Context, onError func(error)) {
+	ticker := time.NewTicker(p.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := p.Push(ctx); err != nil && onError != nil {
+				onError(err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
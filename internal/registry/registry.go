@@ -0,0 +1,195 @@
+// Package registry turns scenario series declarations into registered
+// Prometheus collectors and applies scenario operations to them.
+package registry
+
+import (
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/krajorama/promtestsource/internal/exemplar"
+	"github.com/krajorama/promtestsource/internal/scenario"
+)
+
+// applier is the subset of prometheus collector behaviour a step can
+// drive. Gauges implement Set/Inc/Add; counters implement Inc/Add;
+// histograms and summaries implement Observe/ObserveWithExemplar.
+type applier interface {
+	Set(float64)
+	Inc()
+	Add(float64)
+	Observe(float64)
+	ObserveWithExemplar(float64, prometheus.Labels)
+}
+
+// SeriesRegistry owns the live collectors for a running scenario, keyed by
+// the name they were declared under.
+type SeriesRegistry struct {
+	byName    map[string]applier
+	exemplars *exemplar.Controller
+}
+
+// New builds an empty registry. exemplars may be nil, in which case
+// observations never carry exemplars.
+func New(exemplars *exemplar.Controller) *SeriesRegistry {
+	return &SeriesRegistry{byName: make(map[string]applier), exemplars: exemplars}
+}
+
+// Register constructs and registers the Prometheus collector for spec, and
+// tracks it under spec.Name for later Apply calls.
+func (r *SeriesRegistry) Register(spec scenario.Series, constLabels map[string]string) error {
+	if _, exists := r.byName[spec.Name]; exists {
+		return fmt.Errorf("series %s: already registered", spec.Name)
+	}
+
+	labels := prometheus.Labels{}
+	for k, v := range spec.ConstLabels {
+		labels[k] = v
+	}
+	for k, v := range constLabels {
+		labels[k] = v
+	}
+
+	var a applier
+	switch spec.Type {
+	case scenario.SeriesGauge:
+		g := prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace:   spec.Namespace,
+			Name:        spec.Name,
+			Help:        spec.Help,
+			ConstLabels: labels,
+		})
+		if err := prometheus.Register(g); err != nil {
+			return fmt.Errorf("series %s: %w", spec.Name, err)
+		}
+		a = gaugeApplier{g}
+	case scenario.SeriesCounter:
+		c := prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace:   spec.Namespace,
+			Name:        spec.Name,
+			Help:        spec.Help,
+			ConstLabels: labels,
+		})
+		if err := prometheus.Register(c); err != nil {
+			return fmt.Errorf("series %s: %w", spec.Name, err)
+		}
+		a = counterApplier{c}
+	case scenario.SeriesSummary:
+		s := prometheus.NewSummary(prometheus.SummaryOpts{
+			Namespace:   spec.Namespace,
+			Name:        spec.Name,
+			Help:        spec.Help,
+			ConstLabels: labels,
+			Objectives:  spec.SummaryObjectives,
+		})
+		if err := prometheus.Register(s); err != nil {
+			return fmt.Errorf("series %s: %w", spec.Name, err)
+		}
+		a = newObserverApplier(s)
+	case scenario.SeriesClassicHistogram, scenario.SeriesNativeHistogram:
+		buckets := spec.ClassicBuckets
+		if spec.Type == scenario.SeriesClassicHistogram && len(buckets) == 0 {
+			buckets = prometheus.DefBuckets
+		}
+		opts := prometheus.HistogramOpts{
+			Namespace:   spec.Namespace,
+			Name:        spec.Name,
+			Help:        spec.Help,
+			ConstLabels: labels,
+			Buckets:     buckets,
+		}
+		if spec.Type == scenario.SeriesNativeHistogram {
+			opts.NativeHistogramBucketFactor = spec.NativeHistogram.BucketFactor
+			opts.NativeHistogramMaxBucketNumber = spec.NativeHistogram.MaxBucketNumber
+			opts.NativeHistogramMinResetDuration = spec.NativeHistogram.MinResetDuration
+		}
+		h := prometheus.NewHistogram(opts)
+		if err := prometheus.Register(h); err != nil {
+			return fmt.Errorf("series %s: %w", spec.Name, err)
+		}
+		a = newObserverApplier(h)
+	default:
+		return fmt.Errorf("series %s: unknown type %q", spec.Name, spec.Type)
+	}
+
+	r.byName[spec.Name] = a
+	return nil
+}
+
+// Apply performs op against the named series with the given value. For
+// OpObserve, if the registry was built with an exemplar controller, the
+// controller decides (by rate) whether this observation carries an
+// exemplar.
+func (r *SeriesRegistry) Apply(name string, op scenario.Op, value float64) error {
+	a, ok := r.byName[name]
+	if !ok {
+		return fmt.Errorf("series %s: not registered", name)
+	}
+	switch op {
+	case scenario.OpSet:
+		a.Set(value)
+	case scenario.OpInc:
+		a.Inc()
+	case scenario.OpAdd:
+		a.Add(value)
+	case scenario.OpObserve:
+		if r.exemplars != nil && r.exemplars.ShouldSample() {
+			labels, err := r.exemplars.Labels()
+			if err != nil {
+				return fmt.Errorf("series %s: exemplar labels: %w", name, err)
+			}
+			a.ObserveWithExemplar(value, labels)
+		} else {
+			a.Observe(value)
+		}
+	default:
+		return fmt.Errorf("series %s: unknown op %q", name, op)
+	}
+	return nil
+}
+
+// gaugeApplier adapts prometheus.Gauge to applier; Observe and
+// ObserveWithExemplar are no-ops rather than a silent Set, so an observe
+// step against a gauge changes nothing and reports no error.
+type gaugeApplier struct{ prometheus.Gauge }
+
+func (gaugeApplier) Observe(float64)                                {}
+func (gaugeApplier) ObserveWithExemplar(float64, prometheus.Labels) {}
+
+// counterApplier adapts prometheus.Counter to applier.
+type counterApplier struct{ prometheus.Counter }
+
+func (counterApplier) Set(float64)                                    {}
+func (counterApplier) Observe(float64)                                {}
+func (counterApplier) ObserveWithExemplar(float64, prometheus.Labels) {}
+
+// observerApplier adapts prometheus.Observer (histograms, summaries) to
+// applier. exemplarObserver is non-nil when the underlying collector
+// implements prometheus.ExemplarObserver, which histograms do; summaries
+// do not, so their observations never carry exemplars.
+type observerApplier struct {
+	prometheus.Observer
+	exemplarObserver prometheus.ExemplarObserver
+}
+
+// newObserverApplier wraps obs, capturing its ExemplarObserver capability
+// if it has one.
+func newObserverApplier(obs prometheus.Observer) observerApplier {
+	eo, _ := obs.(prometheus.ExemplarObserver)
+	return observerApplier{Observer: obs, exemplarObserver: eo}
+}
+
+func (observerApplier) Set(float64) {}
+func (observerApplier) Inc()        {}
+func (observerApplier) Add(float64) {}
+
+// ObserveWithExemplar attaches labels to the observation when the
+// underlying collector supports exemplars, falling back to a plain
+// Observe otherwise.
+func (o observerApplier) ObserveWithExemplar(value float64, labels prometheus.Labels) {
+	if o.exemplarObserver != nil {
+		o.exemplarObserver.ObserveWithExemplar(value, labels)
+		return
+	}
+	o.Observe(value)
+}
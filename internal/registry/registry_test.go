@@ -0,0 +1,86 @@
+package registry
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/krajorama/promtestsource/internal/exemplar"
+	"github.com/krajorama/promtestsource/internal/scenario"
+)
+
+// TestApplyObserveWithExemplar registers a histogram with exemplars forced
+// on every observation and checks that the trace/span exemplar labels
+// show up on the expected bucket when the series is scraped in OpenMetrics
+// format.
+func TestApplyObserveWithExemplar(t *testing.T) {
+	rate, err := exemplar.ParseRate("every=1")
+	if err != nil {
+		t.Fatalf("ParseRate: %v", err)
+	}
+	controller := exemplar.NewController(rate, exemplar.TraceContextLabeler{})
+
+	reg := New(controller)
+	spec := scenario.Series{
+		Name:           "registry_test_exemplar_histogram",
+		Help:           "test histogram",
+		Type:           scenario.SeriesClassicHistogram,
+		ClassicBuckets: []float64{1, 2, 5},
+	}
+	if err := reg.Register(spec, nil); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	// 1.5 falls in the le="2.0" bucket.
+	if err := reg.Apply(spec.Name, scenario.OpObserve, 1.5); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+
+	handler := promhttp.HandlerFor(prometheus.DefaultGatherer, promhttp.HandlerOpts{EnableOpenMetrics: true})
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	req, err := http.NewRequest(http.MethodGet, srv.URL, nil)
+	if err != nil {
+		t.Fatalf("NewRequest: %v", err)
+	}
+	req.Header.Set("Accept", "application/openmetrics-text")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET /metrics: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("reading response body: %v", err)
+	}
+
+	var bucketLine string
+	for _, line := range strings.Split(string(body), "\n") {
+		if strings.Contains(line, spec.Name+`_bucket{le="2.0"`) {
+			bucketLine = line
+			break
+		}
+	}
+	if bucketLine == "" {
+		t.Fatalf("expected a %s_bucket{le=\"2.0\"...} sample in OpenMetrics output:\n%s", spec.Name, body)
+	}
+	if !strings.Contains(bucketLine, "trace_id=") {
+		t.Errorf("expected trace_id exemplar label on bucket sample, got: %s", bucketLine)
+	}
+	if !strings.Contains(bucketLine, "span_id=") {
+		t.Errorf("expected span_id exemplar label on bucket sample, got: %s", bucketLine)
+	}
+	// OpenMetrics exemplars carry a trailing unix timestamp after the
+	// exemplar value, e.g. "... # {trace_id=\"...\"} 1.5 1.690000000123e+09".
+	fields := strings.Fields(bucketLine)
+	if len(fields) == 0 || !strings.Contains(fields[len(fields)-1], ".") {
+		t.Errorf("expected a fractional exemplar timestamp at the end of the bucket sample, got: %s", bucketLine)
+	}
+}
@@ -0,0 +1,113 @@
+package replay
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Collector serves a Snapshot's series as const histograms and re-reads
+// its source file whenever told to via Reload (wired to SIGHUP by Watch),
+// so scenarios can be edited without restarting the process.
+type Collector struct {
+	path string
+
+	mu   sync.RWMutex
+	snap *Snapshot
+}
+
+// NewCollector loads path and builds a Collector serving it.
+func NewCollector(path string) (*Collector, error) {
+	snap, err := Load(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Collector{path: path, snap: snap}, nil
+}
+
+// Reload re-reads the snapshot file, replacing the served series on
+// success. A parse failure leaves the previously loaded snapshot in place.
+func (c *Collector) Reload() error {
+	snap, err := Load(c.path)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.snap = snap
+	c.mu.Unlock()
+	return nil
+}
+
+// Watch reloads the snapshot on every SIGHUP until ctx is cancelled,
+// logging (rather than failing on) reload errors.
+func (c *Collector) Watch(ctx context.Context) {
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
+	defer signal.Stop(sighup)
+
+	for {
+		select {
+		case <-sighup:
+			if err := c.Reload(); err != nil {
+				log.Printf("replay: reload of %s failed: %v", c.path, err)
+			} else {
+				log.Printf("replay: reloaded %s", c.path)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Describe intentionally sends nothing: the snapshot's series can change
+// across a SIGHUP reload, so this collector is "unchecked" rather than
+// advertising a fixed descriptor set up front.
+func (c *Collector) Describe(chan<- *prometheus.Desc) {}
+
+// Collect emits one const histogram per series in the currently loaded
+// snapshot, with per-bucket exemplars attached where the snapshot
+// supplies them.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, s := range c.snap.Series {
+		buckets := make(map[float64]uint64, len(s.Buckets))
+		for _, b := range s.Buckets {
+			buckets[b.UpperBound] = b.CumulativeCount
+		}
+
+		desc := prometheus.NewDesc(s.Name, s.Help, nil, s.ConstLabels)
+		metric := prometheus.MustNewConstHistogram(desc, s.SampleCount, s.SampleSum, buckets)
+
+		if exemplars := bucketExemplars(s.Buckets); len(exemplars) > 0 {
+			metric = prometheus.MustNewMetricWithExemplars(metric, exemplars...)
+		}
+		ch <- metric
+	}
+}
+
+func bucketExemplars(buckets []Bucket) []prometheus.Exemplar {
+	var exemplars []prometheus.Exemplar
+	for _, b := range buckets {
+		if b.Exemplar == nil {
+			continue
+		}
+		ts := time.Now()
+		if b.Exemplar.Timestamp != 0 {
+			ts = time.Unix(0, int64(b.Exemplar.Timestamp*float64(time.Second)))
+		}
+		exemplars = append(exemplars, prometheus.Exemplar{
+			Value:     b.Exemplar.Value,
+			Labels:    b.Exemplar.Labels,
+			Timestamp: ts,
+		})
+	}
+	return exemplars
+}
@@ -0,0 +1,150 @@
+// Package replay serves pre-aggregated histograms loaded from a CSV/JSON
+// snapshot file as const metrics, so downstream tooling can be validated
+// against known-good distributions without running a synthetic workload.
+package replay
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Exemplar is the exemplar given with a single bucket. When served it is
+// placed in the bucket its Value falls into, which need not be the bucket
+// it was given with.
+type Exemplar struct {
+	Labels    map[string]string `json:"labels"`
+	Value     float64           `json:"value"`
+	Timestamp float64           `json:"timestamp_unix"` // seconds since epoch; 0 means "unset"
+}
+
+// Bucket is one cumulative bucket of a histogram snapshot.
+type Bucket struct {
+	UpperBound      float64   `json:"upper_bound"`
+	CumulativeCount uint64    `json:"cumulative_count"`
+	Exemplar        *Exemplar `json:"exemplar,omitempty"`
+}
+
+// Series is one pre-aggregated histogram to serve.
+type Series struct {
+	Name        string            `json:"name"`
+	Help        string            `json:"help"`
+	ConstLabels map[string]string `json:"const_labels"`
+	SampleCount uint64            `json:"sample_count"`
+	SampleSum   float64           `json:"sample_sum"`
+	Buckets     []Bucket          `json:"buckets"`
+}
+
+// Snapshot is the top-level document loaded from the -replay file.
+type Snapshot struct {
+	Series []Series `json:"series"`
+}
+
+// Load reads a snapshot from path, dispatching on its extension: ".csv" or
+// anything else (treated as JSON).
+func Load(path string) (*Snapshot, error) {
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		return loadCSV(path)
+	}
+	return loadJSON(path)
+}
+
+func loadJSON(path string) (*Snapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading snapshot file: %w", err)
+	}
+	var snap Snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return nil, fmt.Errorf("parsing snapshot file: %w", err)
+	}
+	return &snap, nil
+}
+
+// loadCSV reads a flat per-bucket CSV with a header row:
+//
+//	series,help,sample_count,sample_sum,upper_bound,cumulative_count,exemplar_trace_id,exemplar_value
+//
+// Rows sharing the same series name are grouped into one Series; the
+// help and sample_count/sample_sum repeat on every row for that series
+// but are only read once.
+func loadCSV(path string) (*Snapshot, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading snapshot file: %w", err)
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	rows, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("parsing snapshot file: %w", err)
+	}
+	if len(rows) < 1 {
+		return nil, fmt.Errorf("snapshot file: empty CSV")
+	}
+
+	header := rows[0]
+	col := make(map[string]int, len(header))
+	for i, name := range header {
+		col[strings.TrimSpace(name)] = i
+	}
+	for _, required := range []string{"series", "sample_count", "sample_sum", "upper_bound", "cumulative_count"} {
+		if _, ok := col[required]; !ok {
+			return nil, fmt.Errorf("snapshot file: missing required column %q", required)
+		}
+	}
+
+	byName := map[string]*Series{}
+	var order []string
+	for _, row := range rows[1:] {
+		name := row[col["series"]]
+		s, ok := byName[name]
+		if !ok {
+			sampleCount, err := strconv.ParseUint(row[col["sample_count"]], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("snapshot file: series %s: bad sample_count: %w", name, err)
+			}
+			sampleSum, err := strconv.ParseFloat(row[col["sample_sum"]], 64)
+			if err != nil {
+				return nil, fmt.Errorf("snapshot file: series %s: bad sample_sum: %w", name, err)
+			}
+			s = &Series{Name: name, SampleCount: sampleCount, SampleSum: sampleSum}
+			if idx, ok := col["help"]; ok {
+				s.Help = row[idx]
+			}
+			byName[name] = s
+			order = append(order, name)
+		}
+
+		upperBound, err := strconv.ParseFloat(row[col["upper_bound"]], 64)
+		if err != nil {
+			return nil, fmt.Errorf("snapshot file: series %s: bad upper_bound: %w", name, err)
+		}
+		cumulativeCount, err := strconv.ParseUint(row[col["cumulative_count"]], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("snapshot file: series %s: bad cumulative_count: %w", name, err)
+		}
+		bucket := Bucket{UpperBound: upperBound, CumulativeCount: cumulativeCount}
+
+		if idx, ok := col["exemplar_trace_id"]; ok && row[idx] != "" {
+			value := 0.0
+			if vIdx, ok := col["exemplar_value"]; ok {
+				value, _ = strconv.ParseFloat(row[vIdx], 64)
+			}
+			bucket.Exemplar = &Exemplar{Labels: map[string]string{"trace_id": row[idx]}, Value: value}
+		}
+
+		s.Buckets = append(s.Buckets, bucket)
+	}
+
+	snap := &Snapshot{}
+	for _, name := range order {
+		snap.Series = append(snap.Series, *byName[name])
+	}
+	return snap, nil
+}
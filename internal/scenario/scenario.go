@@ -0,0 +1,220 @@
+// Package scenario defines the declarative YAML/JSON format used by the
+// -scenario flag: a set of named series plus a timeline of steps that
+// drive them over time.
+package scenario
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"gopkg.in/yaml.v3"
+)
+
+// SeriesType enumerates the kinds of series a scenario can declare.
+type SeriesType string
+
+const (
+	SeriesGauge            SeriesType = "gauge"
+	SeriesCounter          SeriesType = "counter"
+	SeriesSummary          SeriesType = "summary"
+	SeriesClassicHistogram SeriesType = "classic-histogram"
+	SeriesNativeHistogram  SeriesType = "native-histogram"
+)
+
+// NativeHistogramParams mirrors the subset of prometheus.HistogramOpts that
+// controls native histogram bucketing.
+type NativeHistogramParams struct {
+	BucketFactor     float64       `yaml:"bucket_factor"`
+	MaxBucketNumber  uint32        `yaml:"max_buckets"`
+	MinResetDuration time.Duration `yaml:"min_reset_duration"`
+}
+
+// Series declares a single Prometheus collector and the parameters needed
+// to build it.
+type Series struct {
+	Namespace   string            `yaml:"namespace"`
+	Name        string            `yaml:"name"`
+	Help        string            `yaml:"help"`
+	ConstLabels map[string]string `yaml:"const_labels"`
+	Type        SeriesType        `yaml:"type"`
+
+	// ClassicBuckets is used by classic-histogram (DefBuckets when empty)
+	// and, when set, adds classic buckets to a native-histogram; ignored
+	// otherwise.
+	ClassicBuckets []float64 `yaml:"classic_buckets"`
+	// SummaryObjectives is used by summary; ignored otherwise.
+	SummaryObjectives map[float64]float64 `yaml:"summary_objectives"`
+	// NativeHistogram is used by native-histogram; ignored otherwise.
+	NativeHistogram NativeHistogramParams `yaml:"native_histogram"`
+}
+
+// Validate checks that a series declaration is internally consistent.
+func (s Series) Validate() error {
+	if s.Name == "" {
+		return fmt.Errorf("series: name must not be empty")
+	}
+	switch s.Type {
+	case SeriesGauge, SeriesCounter, SeriesSummary, SeriesClassicHistogram, SeriesNativeHistogram:
+	default:
+		return fmt.Errorf("series %s: unknown type %q", s.Name, s.Type)
+	}
+	return nil
+}
+
+// DistributionKind enumerates the supported value generators for an
+// "observe" step.
+type DistributionKind string
+
+const (
+	DistNormal   DistributionKind = "normal"
+	DistUniform  DistributionKind = "uniform"
+	DistConstant DistributionKind = "constant"
+)
+
+// Distribution describes how to sample the values fed to Observe for a
+// step, including how many samples to draw.
+type Distribution struct {
+	Kind   DistributionKind `yaml:"kind"`
+	Mean   float64          `yaml:"mean"`
+	StdDev float64          `yaml:"stddev"`
+	Min    float64          `yaml:"min"`
+	Max    float64          `yaml:"max"`
+	Value  float64          `yaml:"value"`
+	Count  int              `yaml:"count"`
+}
+
+// Op enumerates the operations a step can apply to a series.
+type Op string
+
+const (
+	OpSet     Op = "set"
+	OpInc     Op = "inc"
+	OpAdd     Op = "add"
+	OpObserve Op = "observe"
+)
+
+// Step is a single entry in a scenario's timeline. At is relative to the
+// scenario's start. Repeat/Duration/Rate let a single declaration expand
+// into many applications spread over time (ramps, spikes, steady state).
+type Step struct {
+	At           time.Duration `yaml:"at"`
+	Series       string        `yaml:"series"`
+	Op           Op            `yaml:"op"`
+	Value        float64       `yaml:"value"`
+	Distribution *Distribution `yaml:"distribution"`
+
+	// Repeat, if > 1, applies the step that many times.
+	Repeat int `yaml:"repeat"`
+	// Duration spreads Repeat applications evenly across this window,
+	// starting at At. Zero means apply them back-to-back.
+	Duration time.Duration `yaml:"duration"`
+	// Rate, in applications per second, is an alternative to Repeat: when
+	// set together with Duration it derives Repeat as Rate*Duration.
+	Rate float64 `yaml:"rate"`
+}
+
+// Validate checks that a step references a real op and has sane timeline
+// fields.
+func (s Step) Validate() error {
+	if s.Series == "" {
+		return fmt.Errorf("step at %s: series must not be empty", s.At)
+	}
+	switch s.Op {
+	case OpSet, OpInc, OpAdd, OpObserve:
+	default:
+		return fmt.Errorf("step at %s: unknown op %q", s.At, s.Op)
+	}
+	if s.Rate < 0 || s.Repeat < 0 {
+		return fmt.Errorf("step at %s: rate and repeat must not be negative", s.At)
+	}
+	return nil
+}
+
+// Scenario is the top level document loaded from the -scenario file.
+type Scenario struct {
+	Series []Series `yaml:"series"`
+	Steps  []Step   `yaml:"steps"`
+}
+
+// Validate walks the whole document and returns the first problem found.
+func (sc *Scenario) Validate() error {
+	names := make(map[string]struct{}, len(sc.Series))
+	for _, s := range sc.Series {
+		if err := s.Validate(); err != nil {
+			return err
+		}
+		if _, dup := names[s.Name]; dup {
+			return fmt.Errorf("series %s: declared more than once", s.Name)
+		}
+		names[s.Name] = struct{}{}
+	}
+	for _, st := range sc.Steps {
+		if err := st.Validate(); err != nil {
+			return err
+		}
+		if _, ok := names[st.Series]; !ok {
+			return fmt.Errorf("step at %s: references undeclared series %q", st.At, st.Series)
+		}
+	}
+	return nil
+}
+
+// Load reads and parses a scenario document from path. JSON is valid YAML,
+// so a single decoder handles both extensions.
+func Load(path string) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading scenario file: %w", err)
+	}
+	var sc Scenario
+	if err := yaml.Unmarshal(data, &sc); err != nil {
+		return nil, fmt.Errorf("parsing scenario file: %w", err)
+	}
+	if err := sc.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid scenario: %w", err)
+	}
+	return &sc, nil
+}
+
+// Builtin returns the scenario equivalent of the tool's original
+// hard-coded behaviour: warm up a native histogram with 100000
+// observations of 1.0, then keep observing 1.0 at 10Hz forever. It exists
+// so the pre-scenario CLI mode keeps working unchanged.
+func Builtin() *Scenario {
+	return &Scenario{
+		Series: []Series{
+			{
+				Namespace: "golang",
+				Name:      "manual_histogram",
+				Help:      "This is a histogram with manually selected parameters",
+				Type:      SeriesNativeHistogram,
+				NativeHistogram: NativeHistogramParams{
+					BucketFactor:     1.1,
+					MaxBucketNumber:  100,
+					MinResetDuration: time.Hour,
+				},
+				ClassicBuckets: prometheus.DefBuckets,
+			},
+		},
+		Steps: []Step{
+			{
+				At:     0,
+				Series: "manual_histogram",
+				Op:     OpObserve,
+				Value:  1.0,
+				Repeat: 100000,
+			},
+			{
+				// Rate with no Duration/Repeat means "run at this rate
+				// until the scenario is stopped".
+				At:     0,
+				Series: "manual_histogram",
+				Op:     OpObserve,
+				Value:  1.0,
+				Rate:   10,
+			},
+		},
+	}
+}
@@ -0,0 +1,182 @@
+// Package scheduler drives a scenario's timeline against a
+// registry.SeriesRegistry in real time.
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/krajorama/promtestsource/internal/registry"
+	"github.com/krajorama/promtestsource/internal/scenario"
+)
+
+// Scheduler runs a scenario's steps, expanding repeat/duration/rate into a
+// sequence of timed applications against a SeriesRegistry.
+type Scheduler struct {
+	reg   *registry.SeriesRegistry
+	steps []scenario.Step
+}
+
+// New builds a Scheduler for the given registry and steps.
+func New(reg *registry.SeriesRegistry, steps []scenario.Step) *Scheduler {
+	return &Scheduler{reg: reg, steps: steps}
+}
+
+// application is a single fully-resolved (time, series, op, value) point
+// on the timeline, produced by expanding a Step.
+type application struct {
+	at     time.Duration
+	series string
+	op     scenario.Op
+	value  float64
+}
+
+// isSteadyState reports whether s describes an open-ended rate (no Repeat,
+// no Duration) that should run until the scenario is stopped, rather than
+// being expanded into a fixed-size timeline up front.
+func isSteadyState(s scenario.Step) bool {
+	return s.Rate > 0 && s.Duration == 0 && s.Repeat == 0
+}
+
+// expand turns one Step into one or more applications spread across its
+// Repeat/Duration/Rate window.
+func expand(s scenario.Step) []application {
+	count := s.Repeat
+	if count == 0 {
+		count = 1
+	}
+	if s.Rate > 0 && s.Duration > 0 {
+		count = int(s.Rate * s.Duration.Seconds())
+		if count < 1 {
+			count = 1
+		}
+	}
+
+	values := sampleValues(s, count)
+
+	apps := make([]application, 0, count)
+	for i := 0; i < count; i++ {
+		offset := time.Duration(0)
+		if count > 1 && s.Duration > 0 {
+			offset = s.Duration * time.Duration(i) / time.Duration(count)
+		}
+		apps = append(apps, application{
+			at:     s.At + offset,
+			series: s.Series,
+			op:     s.Op,
+			value:  values[i],
+		})
+	}
+	return apps
+}
+
+// sampleValues returns count values for a step: either count draws from
+// its Distribution, or count copies of its flat Value.
+func sampleValues(s scenario.Step, count int) []float64 {
+	values := make([]float64, count)
+	d := s.Distribution
+	if d == nil {
+		for i := range values {
+			values[i] = s.Value
+		}
+		return values
+	}
+	for i := range values {
+		switch d.Kind {
+		case scenario.DistNormal:
+			values[i] = rand.NormFloat64()*d.StdDev + d.Mean
+		case scenario.DistUniform:
+			values[i] = d.Min + rand.Float64()*(d.Max-d.Min)
+		case scenario.DistConstant:
+			values[i] = d.Value
+		default:
+			values[i] = d.Value
+		}
+	}
+	return values
+}
+
+// Run executes every step's expanded applications in timeline order,
+// blocking until the last finite application has fired or ctx is
+// cancelled. Steady-state steps (open-ended rate, no repeat/duration) run
+// alongside the timeline; when there are any, Run also waits for them to
+// stop, which happens when ctx is cancelled.
+func (s *Scheduler) Run(ctx context.Context) error {
+	var timeline []application
+	var steadyState []scenario.Step
+	for _, step := range s.steps {
+		if isSteadyState(step) {
+			steadyState = append(steadyState, step)
+			continue
+		}
+		timeline = append(timeline, expand(step)...)
+	}
+
+	// Stable sort by time, preserving declaration order for ties.
+	for i := 1; i < len(timeline); i++ {
+		for j := i; j > 0 && timeline[j].at < timeline[j-1].at; j-- {
+			timeline[j], timeline[j-1] = timeline[j-1], timeline[j]
+		}
+	}
+
+	var wg sync.WaitGroup
+	for _, step := range steadyState {
+		wg.Add(1)
+		go func(step scenario.Step) {
+			defer wg.Done()
+			s.runSteadyState(ctx, step)
+		}(step)
+	}
+
+	start := time.Now()
+	for _, app := range timeline {
+		wait := app.at - time.Since(start)
+		if wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				wg.Wait()
+				return ctx.Err()
+			}
+		}
+		if err := s.reg.Apply(app.series, app.op, app.value); err != nil {
+			wg.Wait()
+			return fmt.Errorf("applying step for series %s at %s: %w", app.series, app.at, err)
+		}
+	}
+
+	wg.Wait()
+	return ctx.Err()
+}
+
+// runSteadyState applies step at its fixed rate, starting at step.At,
+// until ctx is cancelled. It stops early, without reporting the error, if
+// an Apply fails.
+func (s *Scheduler) runSteadyState(ctx context.Context, step scenario.Step) {
+	if step.At > 0 {
+		select {
+		case <-time.After(step.At):
+		case <-ctx.Done():
+			return
+		}
+	}
+
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / step.Rate))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			value := sampleValues(step, 1)[0]
+			if err := s.reg.Apply(step.Series, step.Op, value); err != nil {
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -1,20 +1,28 @@
 package main
 
 import (
-	"bufio"
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"flag"
 	"fmt"
 	"log"
 	"net"
 	"net/http"
 	"os"
-	"strconv"
-	//"strings"
+	"strings"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/krajorama/promtestsource/internal/bridge"
+	"github.com/krajorama/promtestsource/internal/exemplar"
+	"github.com/krajorama/promtestsource/internal/pusher"
+	"github.com/krajorama/promtestsource/internal/registry"
+	"github.com/krajorama/promtestsource/internal/replay"
+	"github.com/krajorama/promtestsource/internal/scenario"
+	"github.com/krajorama/promtestsource/internal/scheduler"
 )
 
 const defaultPort = "5001"
@@ -43,16 +51,75 @@ func (v MetricType) String() string {
 type Config struct {
 	ListenAddress string
 	MetricType    string
+	ScenarioFile  string
+
+	BridgeStatsDAddress   string
+	BridgeGraphiteAddress string
+	BridgeMappingConfig   string
+
+	Exemplars           string
+	ExemplarsLabelsFile string
+
+	PushRemoteWriteURL          string
+	PushInterval                time.Duration
+	PushHeaders                 headerFlag
+	PushBasicAuthUsername       string
+	PushBasicAuthPassword       string
+	PushBearerTokenFile         string
+	PushTLSCAFile               string
+	PushTLSCertFile             string
+	PushTLSKeyFile              string
+	PushTLSInsecure             bool
+	PushIncludeNativeHistograms bool
+
+	ReplayFile string
+}
+
+// headerFlag accumulates repeated "-push.header key=val" flags into a map.
+type headerFlag map[string]string
+
+func (h *headerFlag) String() string {
+	return fmt.Sprint(map[string]string(*h))
+}
+
+func (h *headerFlag) Set(value string) error {
+	k, v, ok := strings.Cut(value, "=")
+	if !ok {
+		return fmt.Errorf("-push.header %q: expected key=value", value)
+	}
+	if *h == nil {
+		*h = headerFlag{}
+	}
+	(*h)[k] = v
+	return nil
 }
 
 func (cfg *Config) RegisterFlags(f *flag.FlagSet) {
 	f.StringVar(&cfg.ListenAddress, "bind", fmt.Sprintf(":%s", defaultPort), "Bind address")
 	f.StringVar(&cfg.MetricType, "type", "gauge", "The type of metric to generate: gauge, histogram, floathistogram")
+	f.StringVar(&cfg.ScenarioFile, "scenario", "", "Path to a YAML/JSON scenario file. If unset, runs the built-in single-histogram scenario.")
+	f.StringVar(&cfg.BridgeStatsDAddress, "bridge.statsd-address", "", "Address to listen on for StatsD line protocol (UDP and TCP). Disabled if unset.")
+	f.StringVar(&cfg.BridgeGraphiteAddress, "bridge.graphite-address", "", "Address to listen on for Graphite plaintext protocol (UDP and TCP). Disabled if unset.")
+	f.StringVar(&cfg.BridgeMappingConfig, "bridge.mapping-config", "", "Path to the YAML mapping config used by -bridge.statsd-address/-bridge.graphite-address.")
+	f.StringVar(&cfg.Exemplars, "exemplars", "", "Attach synthetic trace/span exemplars to observations at this rate, e.g. \"every=100\" or \"every=1s\". Disabled if unset.")
+	f.StringVar(&cfg.ExemplarsLabelsFile, "exemplars.labels-file", "", "Path to a \"key=value\" per line file of extra labels merged onto every exemplar.")
+	f.StringVar(&cfg.PushRemoteWriteURL, "push.remote-write-url", "", "Remote-write endpoint to push to on -push.interval, alongside serving /metrics; /metrics is always served. Disabled if unset.")
+	f.DurationVar(&cfg.PushInterval, "push.interval", 15*time.Second, "How often to gather and push to -push.remote-write-url.")
+	f.Var(&cfg.PushHeaders, "push.header", "Extra \"key=value\" HTTP header to send with every push request. Repeatable.")
+	f.StringVar(&cfg.PushBasicAuthUsername, "push.basic-auth-username", "", "Username for HTTP basic auth against the remote-write endpoint.")
+	f.StringVar(&cfg.PushBasicAuthPassword, "push.basic-auth-password", "", "Password for HTTP basic auth against the remote-write endpoint.")
+	f.StringVar(&cfg.PushBearerTokenFile, "push.bearer-token-file", "", "Path to a file containing a bearer token sent as the Authorization header.")
+	f.StringVar(&cfg.PushTLSCAFile, "push.tls-ca-file", "", "Path to a CA bundle used to verify the remote-write endpoint.")
+	f.StringVar(&cfg.PushTLSCertFile, "push.tls-cert-file", "", "Path to a client certificate for mTLS against the remote-write endpoint.")
+	f.StringVar(&cfg.PushTLSKeyFile, "push.tls-key-file", "", "Path to the private key matching -push.tls-cert-file.")
+	f.BoolVar(&cfg.PushTLSInsecure, "push.tls-insecure-skip-verify", false, "Skip TLS certificate verification for the remote-write endpoint.")
+	f.BoolVar(&cfg.PushIncludeNativeHistograms, "push.include-native-histograms", false, "Emit the histograms field of TimeSeries (RW 2.0-style) for native histograms instead of downgrading to classic buckets.")
+	f.StringVar(&cfg.ReplayFile, "replay", "", "Path to a CSV/JSON snapshot of pre-aggregated histograms to serve as const metrics, re-read on SIGHUP. Disabled if unset; overrides -scenario when set.")
 }
 
 var metricTypes = map[string]MetricType{
-	"gauge": Gauge,
-	"histogram": Histogram,
+	"gauge":          Gauge,
+	"histogram":      Histogram,
 	"floathistogram": FloatHistogram,
 }
 
@@ -61,6 +128,20 @@ func Validate(cfg *Config) error {
 	if !ok {
 		return fmt.Errorf("unknown metric type %s", cfg.MetricType)
 	}
+	if (cfg.BridgeStatsDAddress != "" || cfg.BridgeGraphiteAddress != "") && cfg.BridgeMappingConfig == "" {
+		return fmt.Errorf("-bridge.mapping-config is required when -bridge.statsd-address or -bridge.graphite-address is set")
+	}
+	if cfg.Exemplars != "" {
+		if _, err := exemplar.ParseRate(cfg.Exemplars); err != nil {
+			return err
+		}
+	}
+	if cfg.ExemplarsLabelsFile != "" && cfg.Exemplars == "" {
+		return fmt.Errorf("-exemplars.labels-file requires -exemplars to be set")
+	}
+	if cfg.PushTLSKeyFile != "" && cfg.PushTLSCertFile == "" || cfg.PushTLSCertFile != "" && cfg.PushTLSKeyFile == "" {
+		return fmt.Errorf("-push.tls-cert-file and -push.tls-key-file must be set together")
+	}
 	return nil
 }
 
@@ -71,7 +152,7 @@ func main() {
 	flag.Parse()
 
 	err := Validate(cfg)
-	if err!=nil {
+	if err != nil {
 		fmt.Println(err)
 		return
 	}
@@ -86,36 +167,188 @@ func main() {
 	go func() { log.Fatal(server.ListenAndServe()) }()
 
 	labels := map[string]string{
-		"address": address,
-		"port": port,
+		"address":    address,
+		"port":       port,
 		"generation": "20",
 	}
 
-	// mt := metricTypes[cfg.MetricType]
-	// switch mt {
-	// case Gauge:
-	// 	handleGaugeInput(setupGauge(labels))
-	// case Histogram:
-	// 	handleHistogramInput(setupHistogram(labels))
-	// default:
-	// 	panic(fmt.Sprint("Not implemented for ", mt))
-	// }
-
-	// Start count from 100000
-	h := setupHistogram(labels)
-	for i := 0; i < 100000; i++ {
-		h.Observe(1.0)
-	}
-
-	// 10 times per second
-	tick := time.NewTicker(100*time.Millisecond)
-	defer tick.Stop()
-	for {
-		select {
-		case <-tick.C:
-			h.Observe(1.0)
+	if cfg.BridgeMappingConfig != "" {
+		if err := startBridge(cfg); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if cfg.PushRemoteWriteURL != "" {
+		p, err := buildPusher(cfg)
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("Pushing to %s every %s", cfg.PushRemoteWriteURL, cfg.PushInterval)
+		go p.Run(context.Background(), func(err error) { log.Printf("remote-write push: %v", err) })
+	}
+
+	if cfg.ReplayFile != "" {
+		runReplay(cfg)
+		return
+	}
+
+	sc, err := loadScenario(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	exemplars, err := buildExemplarController(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	reg := registry.New(exemplars)
+	for _, series := range sc.Series {
+		if err := reg.Register(series, labels); err != nil {
+			log.Fatal(err)
 		}
 	}
+
+	sched := scheduler.New(reg, sc.Steps)
+	if err := sched.Run(context.Background()); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// buildExemplarController builds the exemplar rate/labeler pair requested
+// via -exemplars, or returns nil if exemplars are disabled. It always
+// labels with TraceContextLabeler, optionally merging in static labels
+// from -exemplars.labels-file.
+func buildExemplarController(cfg *Config) (*exemplar.Controller, error) {
+	if cfg.Exemplars == "" {
+		return nil, nil
+	}
+	rate, err := exemplar.ParseRate(cfg.Exemplars)
+	if err != nil {
+		return nil, err
+	}
+
+	var labeler exemplar.Labeler = exemplar.TraceContextLabeler{}
+	if cfg.ExemplarsLabelsFile != "" {
+		fileLabeler, err := exemplar.NewFileLabeler(cfg.ExemplarsLabelsFile)
+		if err != nil {
+			return nil, err
+		}
+		labeler = exemplar.Chain(labeler, fileLabeler)
+	}
+	return exemplar.NewController(rate, labeler), nil
+}
+
+// buildPusher assembles the RemoteWritePusher requested via
+// -push.remote-write-url and its associated auth/TLS flags.
+func buildPusher(cfg *Config) (*pusher.RemoteWritePusher, error) {
+	tlsConfig, err := buildPushTLSConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	pCfg := pusher.Config{
+		URL:                     cfg.PushRemoteWriteURL,
+		Interval:                cfg.PushInterval,
+		Headers:                 cfg.PushHeaders,
+		Username:                cfg.PushBasicAuthUsername,
+		Password:                cfg.PushBasicAuthPassword,
+		BearerTokenFile:         cfg.PushBearerTokenFile,
+		TLSConfig:               tlsConfig,
+		IncludeNativeHistograms: cfg.PushIncludeNativeHistograms,
+	}
+	return pusher.NewRemoteWritePusher(pCfg, prometheus.DefaultGatherer), nil
+}
+
+func buildPushTLSConfig(cfg *Config) (*tls.Config, error) {
+	if cfg.PushTLSCAFile == "" && cfg.PushTLSCertFile == "" && !cfg.PushTLSInsecure {
+		return nil, nil
+	}
+	tlsConfig := &tls.Config{InsecureSkipVerify: cfg.PushTLSInsecure}
+
+	if cfg.PushTLSCAFile != "" {
+		ca, err := os.ReadFile(cfg.PushTLSCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading -push.tls-ca-file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("-push.tls-ca-file: no certificates found in %s", cfg.PushTLSCAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if cfg.PushTLSCertFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.PushTLSCertFile, cfg.PushTLSKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("loading -push.tls-cert-file/-push.tls-key-file: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+// startBridge loads the mapping config and starts the StatsD/Graphite
+// listeners requested via -bridge.statsd-address/-bridge.graphite-address,
+// each running in its own goroutine. Listener failures are logged rather
+// than fatal, since the scenario should keep running regardless.
+func startBridge(cfg *Config) error {
+	mappingConfig, err := bridge.LoadConfig(cfg.BridgeMappingConfig)
+	if err != nil {
+		return err
+	}
+
+	reg := bridge.NewRegistry(prometheus.DefaultRegisterer)
+	server := bridge.NewServer(mappingConfig, reg)
+
+	go func() {
+		ticker := time.NewTicker(30 * time.Second)
+		defer ticker.Stop()
+		for t := range ticker.C {
+			reg.Sweep(t)
+		}
+	}()
+
+	if cfg.BridgeStatsDAddress != "" {
+		log.Printf("StatsD bridge listening on %s", cfg.BridgeStatsDAddress)
+		go func() {
+			if err := server.ListenStatsD(cfg.BridgeStatsDAddress); err != nil {
+				log.Printf("statsd bridge: %v", err)
+			}
+		}()
+	}
+	if cfg.BridgeGraphiteAddress != "" {
+		log.Printf("Graphite bridge listening on %s", cfg.BridgeGraphiteAddress)
+		go func() {
+			if err := server.ListenGraphite(cfg.BridgeGraphiteAddress); err != nil {
+				log.Printf("graphite bridge: %v", err)
+			}
+		}()
+	}
+	return nil
+}
+
+// runReplay serves the -replay snapshot file forever, reloading it on
+// every SIGHUP, instead of running a scenario.
+func runReplay(cfg *Config) {
+	collector, err := replay.NewCollector(cfg.ReplayFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	prometheus.MustRegister(collector)
+
+	log.Printf("Replaying %s (reload with SIGHUP)", cfg.ReplayFile)
+	collector.Watch(context.Background())
+}
+
+// loadScenario returns the scenario named by -scenario, or the built-in
+// scenario (the tool's original hard-coded behaviour) when the flag is
+// unset.
+func loadScenario(cfg *Config) (*scenario.Scenario, error) {
+	if cfg.ScenarioFile == "" {
+		return scenario.Builtin(), nil
+	}
+	return scenario.Load(cfg.ScenarioFile)
 }
 
 // getAddressAndPort always defines a non empty address and port
@@ -136,78 +369,3 @@ func getAddressAndPort(listenAddress string) (string, string) {
 
 	return address, port
 }
-
-// func setupGauge(labels map[string]string) prometheus.Gauge {
-// 	gauge := prometheus.NewGauge(
-// 		prometheus.GaugeOpts{
-// 			Namespace: "golang",
-// 			Name:      "manual_gauge",
-// 			Help:      "This is my manual gauge",
-// 			ConstLabels: labels,
-// 		})
-// 	prometheus.MustRegister(gauge)
-// 	return gauge
-// }
-
-// func handleGaugeInput(gauge prometheus.Gauge) {
-// 	currentValue := 0.0
-// 	gauge.Set(currentValue)
-// 	scanner := bufio.NewScanner(os.Stdin)
-// 	scan := func() bool {
-// 		fmt.Printf("Set metric to x or add with +x (current: %v): ", currentValue)
-// 		return scanner.Scan()
-// 	}
-// 	for scan() {
-// 		textToParse := scanner.Text()
-// 		isAdd := false
-// 		if strings.HasPrefix(textToParse, "+") {
-// 			isAdd = true
-// 			textToParse = strings.TrimPrefix(textToParse, "+")
-// 		}
-// 		newValue, error := strconv.ParseFloat(textToParse, 64)
-// 		if error != nil {
-// 			continue
-// 		}
-// 		if isAdd {
-// 			currentValue += newValue
-// 		} else {
-// 			currentValue = newValue
-// 		}
-// 		gauge.Set(currentValue)
-// 	}
-// }
-
-func setupHistogram(labels map[string]string) prometheus.Histogram {
-	histogram := prometheus.NewHistogram(
-		prometheus.HistogramOpts{
-			Namespace: "golang",
-			Name: "manual_histogram",
-			Help: "This is a histogram with manually selected parameters",
-			ConstLabels: labels,
-			NativeHistogramBucketFactor: 1.1,
-			NativeHistogramMaxBucketNumber: 100,
-			NativeHistogramMinResetDuration: 1*time.Hour,
-			Buckets: prometheus.DefBuckets,
-	})
-	prometheus.MustRegister(histogram)
-	//histogram.(prometheus.ExemplarObserver).ObserveWithExemplar(1.0, prometheus.Labels{"foo": "bar1"})
-	// time.Sleep(1*time.Second)
-	// histogram.(prometheus.ExemplarObserver).ObserveWithExemplar(4.0, prometheus.Labels{"foo": "bar2"})
-	return histogram
-}
-
-func handleHistogramInput(histogram prometheus.Histogram) {
-	scanner := bufio.NewScanner(os.Stdin)
-	scan := func() bool {
-		fmt.Printf("Make an observation:")
-		return scanner.Scan()
-	}
-	for scan() {
-		newValue, error := strconv.ParseFloat(scanner.Text(), 64)
-		//histogram.Observe(newValue)
-		histogram.(prometheus.ExemplarObserver).ObserveWithExemplar(newValue, prometheus.Labels{"foo": "bar3"})
-		if error != nil {
-			continue
-		}
-	}
-}